@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/sagiforbes/banai/infra"
+)
+
+//RunSecretsCommand implements "banai secrets add|rm|list", letting a
+//persistent secret store be managed without running a pipeline. args is the
+//command line after the "secrets" subcommand.
+func RunSecretsCommand(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: banai secrets add|rm|list --store <path> --passphrase <passphrase> [args...]")
+	}
+
+	fs := flag.NewFlagSet("secrets", flag.ContinueOnError)
+	storePath := fs.String("store", "", "path to the encrypted secret store file")
+	passphrase := fs.String("passphrase", "", "passphrase protecting the secret store")
+	if e := fs.Parse(args[1:]); e != nil {
+		return e
+	}
+	if *storePath == "" || *passphrase == "" {
+		return fmt.Errorf("--store and --passphrase are required")
+	}
+
+	b := infra.NewBanai()
+	defer b.Close()
+
+	if e := b.LoadSecretStore(*storePath, *passphrase); e != nil {
+		return e
+	}
+
+	switch args[0] {
+	case "list":
+		for _, ref := range b.ListSecrets() {
+			fmt.Printf("%s\t%s\t%s\n", ref.ID, ref.Type, ref.CreatedAt.Format("2006-01-02T15:04:05"))
+		}
+		return nil
+
+	case "rm":
+		rest := fs.Args()
+		if len(rest) != 1 {
+			return fmt.Errorf("usage: banai secrets rm --store <path> --passphrase <passphrase> <secretID>")
+		}
+		if e := b.RemoveSecret(rest[0]); e != nil {
+			return e
+		}
+		return b.SaveSecretStore()
+
+	case "add":
+		rest := fs.Args()
+		if len(rest) != 2 {
+			return fmt.Errorf("usage: banai secrets add --store <path> --passphrase <passphrase> <secretID> <value>")
+		}
+		b.AddStringSecret(rest[0], rest[1])
+		return b.SaveSecretStore()
+
+	default:
+		return fmt.Errorf("unknown secrets subcommand %q", args[0])
+	}
+}
+
+//Main is the entry point a top level main() wires "banai secrets ..." to
+func Main() {
+	if len(os.Args) < 2 || os.Args[1] != "secrets" {
+		return
+	}
+	if e := RunSecretsCommand(os.Args[2:]); e != nil {
+		fmt.Fprintln(os.Stderr, e)
+		os.Exit(1)
+	}
+}
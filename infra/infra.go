@@ -1,15 +1,15 @@
 package infra
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 
 	"github.com/dop251/goja"
-	"github.com/google/uuid"
-	"github.com/sagiforbes/banai/utils/fsutils"
 	"github.com/sirupsen/logrus"
 )
 
@@ -21,24 +21,43 @@ type Banai struct {
 	Jse          *goja.Runtime
 	TmpDir       string
 	Logger       *logrus.Logger
+	Secrets      *SecretsAPI `json:"secrets"`
+	//Stdout and Stderr are redacting wrappers around os.Stdout/os.Stderr; the
+	//JS runtime's print()/console output should be written through these so
+	//loaded secret values never reach the terminal or CI logs
+	Stdout io.Writer
+	Stderr io.Writer
+
 	stashFolder  string
 	secretFolder string
 
-	secrets map[string]secretStruct
+	secrets         map[string]secretEntry
+	store           *secretStoreState
+	redactor        *redactingHook
+	secretProviders *secretProviderRegistry
 }
 
 //NewBanai create new banai struct object
 func NewBanai() *Banai {
 	ret := &Banai{
-		Jse:     goja.New(),
-		Logger:  logrus.New(),
-		secrets: make(map[string]secretStruct),
+		Jse:             goja.New(),
+		Logger:          logrus.New(),
+		secrets:         make(map[string]secretEntry),
+		store:           &secretStoreState{},
+		redactor:        newRedactingHook(),
+		secretProviders: newSecretProviderRegistry(),
 	}
+	ret.Secrets = &SecretsAPI{b: ret}
+	ret.Logger.AddHook(ret.redactor)
+	ret.Stdout = &redactingWriter{hook: ret.redactor, out: os.Stdout}
+	ret.Stderr = &redactingWriter{hook: ret.redactor, out: os.Stderr}
 	ret.Jse.SetFieldNameMapper(goja.TagFieldNameMapper("json", true))
 	ret.TmpDir, _ = filepath.Abs("./.banai")
 	ret.stashFolder = filepath.Join(ret.TmpDir, "stash")
 	ret.secretFolder = filepath.Join(ret.TmpDir, "sec")
-	os.RemoveAll(ret.stashFolder)
+	//the stash is a content-addressable store keyed by chunk/manifest hash, so
+	//it must survive across runs for dedup to do anything - only the
+	//per-run secret scratch area gets wiped on startup
 	os.MkdirAll(ret.stashFolder, 0700)
 	os.RemoveAll(ret.secretFolder)
 	os.MkdirAll(ret.secretFolder, 0700)
@@ -52,84 +71,55 @@ func (b Banai) PanicOnError(e error, t ...string) {
 		var msg string
 		if t != nil {
 			msg = fmt.Sprintf("%s %s", t, e)
-			b.Logger.Error(msg)
-			panic(b.Jse.ToValue(msg))
-
 		} else {
 			msg = fmt.Sprint(e)
-			b.Logger.Error(msg)
-			panic(b.Jse.ToValue(msg))
 		}
 
+		b.Logger.Error(msg)
+		panic(b.Jse.ToValue(b.redactor.redact(msg)))
 	}
 }
 
-//Close should be call at the end of using banai to remove all allocated resource during banai execution
+//Close should be call at the end of using banai to remove all allocated resource during banai execution.
+//The content-addressable stash under TmpDir/stash is left in place so a later run can still dedupe
+//against it; only the per-run secret scratch area is removed.
 func (b Banai) Close() {
-	os.RemoveAll(b.TmpDir)
+	os.RemoveAll(b.secretFolder)
 
 }
 
 //*********************************************************************************
-
-//Save stashs file CONTENT
-func (b Banai) Save(fileName string) (string, error) {
-	abs, e := filepath.Abs(fileName)
-	if e != nil {
-		return "", e
-	}
-	stashID := uuid.NewString()
-
-	e = fsutils.CopyfsItem(abs, stashID)
-	if e != nil {
-		return "", e
-	}
-	return stashID, nil
-}
-
-//Load restore the CONTENT of a previously stashed file
-func (b Banai) Load(stashID string) ([]byte, error) {
-	path := filepath.Join(b.stashFolder, stashID)
-	_, e := os.Stat(path)
-	if e != nil {
-		return nil, e
-	}
-
-	f, e := ioutil.ReadFile(path)
-	if e != nil {
-		return nil, e
-	}
-
-	return f, nil
-
-}
-
+//Save/Load and the rest of the content-addressable stash live in stash.go
 //*********************************************************************************
 
 //AddStringSecret add secret string
-func (b Banai) AddStringSecret(secretID string, value string) {
-	b.secrets[secretID] = secretText{
+func (b Banai) AddStringSecret(secretID string, value string, opts ...SecretOptions) {
+	b.secrets[secretID] = newSecretEntry(secretText{
 		Text: value,
-	}
+	}, opts...)
+	b.redactor.register(value, secretID)
 
 }
 
 //AddSSHWithPrivate add secret string
-func (b Banai) AddSSHWithPrivate(secretID string, user string, privateKey string, passphrase string) {
-	b.secrets[secretID] = secretSSHWithPrivate{
+func (b Banai) AddSSHWithPrivate(secretID string, user string, privateKey string, passphrase string, opts ...SecretOptions) {
+	b.secrets[secretID] = newSecretEntry(secretSSHWithPrivate{
 		User:       user,
 		PrivateKey: privateKey,
 		Passphrase: passphrase,
-	}
+	}, opts...)
+	b.redactor.register(privateKey, secretID)
+	b.redactor.register(passphrase, secretID)
 
 }
 
 //AddUserPassword secret of type user name password
-func (b Banai) AddUserPassword(secretID, user, password string) {
-	b.secrets[secretID] = secretUserPassword{
+func (b Banai) AddUserPassword(secretID, user, password string, opts ...SecretOptions) {
+	b.secrets[secretID] = newSecretEntry(secretUserPassword{
 		User:     user,
 		Password: password,
-	}
+	}, opts...)
+	b.redactor.register(password, secretID)
 }
 
 //*********************************************************************************
@@ -154,6 +144,10 @@ type SSHWithPrivate struct {
 	User           string `json:"user,omitempty"`
 	PrivatekeyFile string `json:"privateKeyFile,omitempty"`
 	Passfrase      string `json:"passfrase,omitempty"`
+	//rawPrivateKey carries the key bytes a provider materialized into
+	//PrivatekeyFile, so registerSecretInfo can add them as a redaction needle.
+	//Unexported: it never reaches JS or gets marshaled, only registered.
+	rawPrivateKey string
 }
 
 //GetType get secret info type
@@ -174,10 +168,29 @@ func (t UserPassword) GetType() string {
 
 //GetSecret add secret string
 func (b Banai) GetSecret(secretID string) (SecretInfo, error) {
-	v, ok := b.secrets[secretID]
+	if name, ref, ok := b.secretProviders.splitProviderRef(secretID); ok {
+		provider, ok := b.secretProviders.get(name)
+		if !ok {
+			return nil, fmt.Errorf("no secret provider registered as %q", name)
+		}
+		ctx := context.WithValue(context.Background(), secretFolderKey{}, b.secretFolder)
+		info, e := provider.Fetch(ctx, ref)
+		if e != nil {
+			return nil, e
+		}
+		b.redactor.registerSecretInfo(secretID, info)
+		return info, nil
+	}
+
+	if b.expireIfNeeded(secretID) {
+		return nil, ErrSecretNotFound
+	}
+
+	entry, ok := b.secrets[secretID]
 	if !ok {
 		return nil, ErrSecretNotFound
 	}
+	v := entry.value
 
 	var ret SecretInfo
 
@@ -194,6 +207,8 @@ func (b Banai) GetSecret(secretID string) (SecretInfo, error) {
 		if err != nil {
 			return nil, ErrSecretNotFound
 		}
+		entry.keyFile = fn
+		b.secrets[secretID] = entry
 		s := SSHWithPrivate{
 			User:           v.(secretSSHWithPrivate).User,
 			PrivatekeyFile: fn,
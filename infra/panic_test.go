@@ -0,0 +1,34 @@
+package infra
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/dop251/goja"
+)
+
+func TestPanicOnErrorRedactsSecretValues(t *testing.T) {
+	b := NewBanai()
+	defer b.Close()
+	defer os.RemoveAll(b.TmpDir)
+
+	b.AddStringSecret("token", "sekrit-value")
+
+	defer func() {
+		r := recover()
+		if r == nil {
+			t.Fatal("expected PanicOnError to panic")
+		}
+		v, ok := r.(goja.Value)
+		if !ok {
+			t.Fatalf("panic value type = %T, want goja.Value", r)
+		}
+		if strings.Contains(v.String(), "sekrit-value") {
+			t.Fatalf("panic value leaked secret: %q", v.String())
+		}
+	}()
+
+	b.PanicOnError(fmt.Errorf("call failed using %s", "sekrit-value"))
+}
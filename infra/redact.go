@@ -0,0 +1,111 @@
+package infra
+
+import (
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+//redactingHook is a logrus.Hook that scrubs loaded secret values out of every
+//log entry before it reaches the formatter
+type redactingHook struct {
+	mu      sync.RWMutex
+	needles map[string]string // secret value -> secret ID
+}
+
+func newRedactingHook() *redactingHook {
+	return &redactingHook{needles: make(map[string]string)}
+}
+
+//register adds value as a needle to scrub, labelled with id in the redacted
+//output. Empty values are ignored since they would match everything.
+func (h *redactingHook) register(value string, id string) {
+	if value == "" {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.needles[value] = id
+}
+
+//registerSecretInfo registers the sensitive fields of a SecretInfo fetched
+//from an external provider under id
+func (h *redactingHook) registerSecretInfo(id string, info SecretInfo) {
+	switch v := info.(type) {
+	case TextSecret:
+		h.register(v.Text, id)
+	case UserPassword:
+		h.register(v.Password, id)
+	case SSHWithPrivate:
+		h.register(v.Passfrase, id)
+		h.register(v.rawPrivateKey, id)
+	}
+}
+
+//registerSecretStruct registers the sensitive fields of a locally stored
+//secret value under id - used when secrets are loaded in bulk from the
+//persistent store, bypassing the Add* calls that normally do this
+func (h *redactingHook) registerSecretStruct(id string, value secretStruct) {
+	switch v := value.(type) {
+	case secretText:
+		h.register(v.Text, id)
+	case secretSSHWithPrivate:
+		h.register(v.PrivateKey, id)
+		h.register(v.Passphrase, id)
+	case secretUserPassword:
+		h.register(v.Password, id)
+	}
+}
+
+//unregister removes every needle registered under id
+func (h *redactingHook) unregister(id string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for value, existing := range h.needles {
+		if existing == id {
+			delete(h.needles, value)
+		}
+	}
+}
+
+func (h *redactingHook) redact(s string) string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for value, id := range h.needles {
+		s = strings.ReplaceAll(s, value, "[REDACTED:"+id+"]")
+	}
+	return s
+}
+
+//Levels makes the hook fire on every log level
+func (h *redactingHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+//Fire scrubs secret values out of the entry's message and string fields
+func (h *redactingHook) Fire(entry *logrus.Entry) error {
+	entry.Message = h.redact(entry.Message)
+	for k, v := range entry.Data {
+		if s, ok := v.(string); ok {
+			entry.Data[k] = h.redact(s)
+		}
+	}
+	return nil
+}
+
+//redactingWriter wraps an io.Writer, scrubbing secret values from anything
+//written through it before passing it on. Used to wrap os.Stdout/os.Stderr so
+//JS print() output can't leak a loaded secret either.
+type redactingWriter struct {
+	hook *redactingHook
+	out  io.Writer
+}
+
+func (w *redactingWriter) Write(p []byte) (int, error) {
+	if _, e := w.out.Write([]byte(w.hook.redact(string(p)))); e != nil {
+		return 0, e
+	}
+	return len(p), nil
+}
@@ -0,0 +1,185 @@
+package infra
+
+import (
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+//SecretOptions carries metadata and lifetime settings for a secret passed to
+//one of the Add* calls
+type SecretOptions struct {
+	Labels map[string]string `json:"labels,omitempty"`
+	//TTL, when set, causes the secret to be treated as expired - and removed,
+	//shredding any on-disk key file - once it has been stored for this long
+	TTL time.Duration `json:"ttl,omitempty"`
+}
+
+//secretEntry wraps a stored secret value together with the bookkeeping
+//needed by the list/inspect/remove/TTL surface
+type secretEntry struct {
+	value     secretStruct
+	options   SecretOptions
+	createdAt time.Time
+	//keyFile is set once an ssh secret's private key has been materialized to
+	//disk by GetSecret, so RemoveSecret can shred it
+	keyFile string
+}
+
+func newSecretEntry(value secretStruct, opts ...SecretOptions) secretEntry {
+	e := secretEntry{
+		value:     value,
+		createdAt: time.Now(),
+	}
+	if len(opts) > 0 {
+		e.options = opts[0]
+	}
+	return e
+}
+
+func (e secretEntry) expired() bool {
+	if e.options.TTL <= 0 {
+		return false
+	}
+	return time.Since(e.createdAt) >= e.options.TTL
+}
+
+//SecretRef identifies a stored secret without exposing its value
+type SecretRef struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+//SecretMeta is the full metadata of a stored secret, returned by InspectSecret
+type SecretMeta struct {
+	ID        string            `json:"id"`
+	Type      string            `json:"type"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	CreatedAt time.Time         `json:"createdAt"`
+	ExpiresAt *time.Time        `json:"expiresAt,omitempty"`
+}
+
+//ListSecrets enumerates the currently stored secret IDs. Plaintext values are
+//never included.
+func (b Banai) ListSecrets() []SecretRef {
+	b.expireAll()
+
+	refs := make([]SecretRef, 0, len(b.secrets))
+	for id, e := range b.secrets {
+		refs = append(refs, SecretRef{
+			ID:        id,
+			Type:      e.value.GetType(),
+			CreatedAt: e.createdAt,
+		})
+	}
+	return refs
+}
+
+//HasSecret reports whether secretID is currently stored and not expired
+func (b Banai) HasSecret(secretID string) bool {
+	if b.expireIfNeeded(secretID) {
+		return false
+	}
+	_, ok := b.secrets[secretID]
+	return ok
+}
+
+//RemoveSecret deletes secretID, shredding its on-disk SSH key file if one was
+//materialized by a prior GetSecret call
+func (b Banai) RemoveSecret(secretID string) error {
+	e, ok := b.secrets[secretID]
+	if !ok {
+		return ErrSecretNotFound
+	}
+
+	if e.keyFile != "" {
+		shredFile(e.keyFile)
+	}
+	delete(b.secrets, secretID)
+	b.redactor.unregister(secretID)
+	return nil
+}
+
+//InspectSecret returns metadata about secretID without decrypting or
+//returning its value
+func (b Banai) InspectSecret(secretID string) (SecretMeta, error) {
+	if b.expireIfNeeded(secretID) {
+		return SecretMeta{}, ErrSecretNotFound
+	}
+
+	e, ok := b.secrets[secretID]
+	if !ok {
+		return SecretMeta{}, ErrSecretNotFound
+	}
+
+	meta := SecretMeta{
+		ID:        secretID,
+		Type:      e.value.GetType(),
+		Labels:    e.options.Labels,
+		CreatedAt: e.createdAt,
+	}
+	if e.options.TTL > 0 {
+		expiresAt := e.createdAt.Add(e.options.TTL)
+		meta.ExpiresAt = &expiresAt
+	}
+	return meta, nil
+}
+
+//expireIfNeeded removes secretID if its TTL has elapsed, reporting whether it
+//removed it; an ID that is not stored (including one already expired away by
+//an earlier call) reports false
+func (b Banai) expireIfNeeded(secretID string) bool {
+	e, ok := b.secrets[secretID]
+	if !ok {
+		return false
+	}
+	if !e.expired() {
+		return false
+	}
+
+	if e.keyFile != "" {
+		shredFile(e.keyFile)
+	}
+	delete(b.secrets, secretID)
+	b.redactor.unregister(secretID)
+	return true
+}
+
+func (b Banai) expireAll() {
+	for id := range b.secrets {
+		b.expireIfNeeded(id)
+	}
+}
+
+//shredFile overwrites path with zeros before removing it, best-effort
+func shredFile(path string) {
+	if info, e := os.Stat(path); e == nil {
+		zeros := make([]byte, info.Size())
+		_ = ioutil.WriteFile(path, zeros, 0600)
+	}
+	_ = os.Remove(path)
+}
+
+//*********************************************************************************
+//JS bindings under banai.secrets.*
+
+//List mirrors Banai.ListSecrets for the JS runtime
+func (s *SecretsAPI) List() []SecretRef {
+	return s.b.ListSecrets()
+}
+
+//Has mirrors Banai.HasSecret for the JS runtime
+func (s *SecretsAPI) Has(secretID string) bool {
+	return s.b.HasSecret(secretID)
+}
+
+//Remove mirrors Banai.RemoveSecret for the JS runtime
+func (s *SecretsAPI) Remove(secretID string) error {
+	return s.b.RemoveSecret(secretID)
+}
+
+//Inspect mirrors Banai.InspectSecret for the JS runtime
+func (s *SecretsAPI) Inspect(secretID string) (SecretMeta, error) {
+	return s.b.InspectSecret(secretID)
+}
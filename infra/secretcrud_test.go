@@ -0,0 +1,61 @@
+package infra
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestSecretCRUDAndTTLExpiry(t *testing.T) {
+	b := NewBanai()
+	defer b.Close()
+	defer os.RemoveAll(b.TmpDir)
+
+	b.AddStringSecret("short-lived", "value", SecretOptions{
+		Labels: map[string]string{"env": "test"},
+		TTL:    10 * time.Millisecond,
+	})
+	b.AddStringSecret("long-lived", "other-value")
+
+	if !b.HasSecret("short-lived") {
+		t.Fatal("HasSecret = false immediately after Add, want true")
+	}
+
+	refs := b.ListSecrets()
+	if len(refs) != 2 {
+		t.Fatalf("ListSecrets returned %d entries, want 2", len(refs))
+	}
+
+	meta, e := b.InspectSecret("short-lived")
+	if e != nil {
+		t.Fatalf("InspectSecret: %v", e)
+	}
+	if meta.Labels["env"] != "test" {
+		t.Fatalf("InspectSecret labels = %v, want env=test", meta.Labels)
+	}
+	if meta.ExpiresAt == nil {
+		t.Fatal("InspectSecret ExpiresAt = nil, want a deadline for a TTL'd secret")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	if b.HasSecret("short-lived") {
+		t.Fatal("HasSecret = true after TTL elapsed, want false")
+	}
+	if _, e := b.GetSecret("short-lived"); e != ErrSecretNotFound {
+		t.Fatalf("GetSecret after TTL elapsed = %v, want ErrSecretNotFound", e)
+	}
+	if !b.HasSecret("long-lived") {
+		t.Fatal("unrelated secret without a TTL was expired too")
+	}
+
+	if e := b.RemoveSecret("long-lived"); e != nil {
+		t.Fatalf("RemoveSecret: %v", e)
+	}
+	if b.HasSecret("long-lived") {
+		t.Fatal("HasSecret = true after RemoveSecret, want false")
+	}
+	if e := b.RemoveSecret("long-lived"); e != ErrSecretNotFound {
+		t.Fatalf("RemoveSecret on missing secret = %v, want ErrSecretNotFound", e)
+	}
+}
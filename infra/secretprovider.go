@@ -0,0 +1,151 @@
+package infra
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+//secretFolderKey is the context key under which GetSecret stashes
+//b.secretFolder, so providers that must materialize key material to disk
+//(see VaultProvider) can write it next to locally-added secrets instead of
+//the OS-global temp dir, keeping it subject to the same Close() cleanup.
+type secretFolderKey struct{}
+
+//secretFolderFromContext returns the secret folder stashed in ctx by
+//GetSecret, if any.
+func secretFolderFromContext(ctx context.Context) (string, bool) {
+	folder, ok := ctx.Value(secretFolderKey{}).(string)
+	return folder, ok
+}
+
+//SecretProvider resolves secrets kept in an external system. An ID of the
+//form "name:ref" is routed to the provider registered under "name", and ref
+//is whatever that provider needs to locate the secret (a Vault path, an SSM
+//parameter name, ...).
+type SecretProvider interface {
+	//Kind identifies the provider type, e.g. "vault" or "ssm"
+	Kind() string
+	//Fetch resolves ref into a secret value
+	Fetch(ctx context.Context, ref string) (SecretInfo, error)
+}
+
+//secretProviderRegistry holds the secret providers registered for one Banai
+//run, keyed by the name they were registered under. It's held on Banai by
+//pointer (next to store/redactor) so the methods on Banai, which all take a
+//value receiver, share one registry instead of each copy getting its own -
+//and so two Banai instances in the same process never see each other's
+//providers. The mutex guards concurrent AddProvider/GetSecret calls against
+//the same run.
+type secretProviderRegistry struct {
+	mu        sync.RWMutex
+	providers map[string]SecretProvider
+}
+
+func newSecretProviderRegistry() *secretProviderRegistry {
+	return &secretProviderRegistry{providers: make(map[string]SecretProvider)}
+}
+
+//register makes provider resolvable through GetSecret for IDs prefixed with
+//"name:"
+func (r *secretProviderRegistry) register(name string, provider SecretProvider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.providers[name] = provider
+}
+
+func (r *secretProviderRegistry) get(name string) (SecretProvider, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	p, ok := r.providers[name]
+	return p, ok
+}
+
+//splitProviderRef splits a secret ID of the form "name:ref" into its parts.
+//ok is false when secretID does not look like a provider reference, or when
+//its "name" part is not a registered provider, in which case it should be
+//resolved against the local secret map instead.
+func (r *secretProviderRegistry) splitProviderRef(secretID string) (name string, ref string, ok bool) {
+	idx := strings.Index(secretID, ":")
+	if idx <= 0 {
+		return "", "", false
+	}
+	name, ref = secretID[:idx], secretID[idx+1:]
+	if _, registered := r.get(name); !registered {
+		return "", "", false
+	}
+	return name, ref, true
+}
+
+//ProviderFactory builds a SecretProvider from the configuration map passed to
+//banai.secrets.addProvider
+type ProviderFactory func(config map[string]interface{}) (SecretProvider, error)
+
+//providerFactories registers provider *types* (e.g. "vault", "ssm"), which
+//are compiled-in and declared once via init(), so - unlike provider
+//instances - it stays process-global; the mutex only guards against
+//RegisterProviderFactory racing a lookup.
+var (
+	providerFactoriesMu sync.RWMutex
+	providerFactories   = map[string]ProviderFactory{}
+)
+
+//RegisterProviderFactory makes a provider type constructible by name from
+//banai.secrets.addProvider({type: name, ...})
+func RegisterProviderFactory(name string, factory ProviderFactory) {
+	providerFactoriesMu.Lock()
+	defer providerFactoriesMu.Unlock()
+	providerFactories[name] = factory
+}
+
+func getProviderFactory(name string) (ProviderFactory, bool) {
+	providerFactoriesMu.RLock()
+	defer providerFactoriesMu.RUnlock()
+	f, ok := providerFactories[name]
+	return f, ok
+}
+
+//SecretsAPI exposes secret related operations to the JS runtime under
+//banai.secrets
+type SecretsAPI struct {
+	b *Banai
+}
+
+//ProviderOptions is the single options object JS passes to
+//banai.secrets.addProvider({name, type, config})
+type ProviderOptions struct {
+	Name   string                 `json:"name"`
+	Type   string                 `json:"type"`
+	Config map[string]interface{} `json:"config"`
+}
+
+//AddProvider registers a secret provider instance under opts.Name, so secret
+//IDs of the form "name:ref" resolve through it
+func (s *SecretsAPI) AddProvider(opts ProviderOptions) error {
+	factory, ok := getProviderFactory(opts.Type)
+	if !ok {
+		return fmt.Errorf("unknown secret provider type %q", opts.Type)
+	}
+
+	provider, e := factory(opts.Config)
+	if e != nil {
+		return e
+	}
+
+	s.b.secretProviders.register(opts.Name, provider)
+	return nil
+}
+
+func configString(config map[string]interface{}, key string) string {
+	v, _ := config[key].(string)
+	return v
+}
+
+func configBool(config map[string]interface{}, key string, def bool) bool {
+	v, ok := config[key].(bool)
+	if !ok {
+		return def
+	}
+	return v
+}
@@ -0,0 +1,103 @@
+package infra
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+func init() {
+	RegisterProviderFactory("ssm", newSSMProviderFromConfig)
+}
+
+//SSMConfig configures an SSMProvider
+type SSMConfig struct {
+	Region     string `json:"region,omitempty"`
+	Decrypt    bool   `json:"decrypt,omitempty"`
+	AssumeRole string `json:"assumeRole,omitempty"`
+}
+
+//SSMProvider fetches secrets from AWS Systems Manager Parameter Store
+type SSMProvider struct {
+	client  *ssm.SSM
+	decrypt bool
+}
+
+//NewSSMProvider builds an SSMProvider from cfg
+func NewSSMProvider(cfg SSMConfig) (*SSMProvider, error) {
+	sess, e := session.NewSession(&aws.Config{
+		Region: aws.String(cfg.Region),
+	})
+	if e != nil {
+		return nil, e
+	}
+
+	var client *ssm.SSM
+	if cfg.AssumeRole != "" {
+		creds := stscreds.NewCredentials(sess, cfg.AssumeRole)
+		client = ssm.New(sess, &aws.Config{Credentials: creds})
+	} else {
+		client = ssm.New(sess)
+	}
+
+	return &SSMProvider{
+		client:  client,
+		decrypt: cfg.Decrypt,
+	}, nil
+}
+
+func newSSMProviderFromConfig(config map[string]interface{}) (SecretProvider, error) {
+	cfg := SSMConfig{
+		Region:     configString(config, "region"),
+		Decrypt:    configBool(config, "decrypt", true),
+		AssumeRole: configString(config, "assumeRole"),
+	}
+
+	return NewSSMProvider(cfg)
+}
+
+//Kind identifies this provider as "ssm"
+func (s *SSMProvider) Kind() string {
+	return "ssm"
+}
+
+//Fetch reads a parameter from SSM. ref is the parameter name, optionally
+//followed by "#field" selecting a field out of a JSON-encoded parameter
+//value, e.g. "/banai/prod/deploy#password".
+func (s *SSMProvider) Fetch(ctx context.Context, ref string) (SecretInfo, error) {
+	name, field := splitSSMRef(ref)
+
+	out, e := s.client.GetParameterWithContext(ctx, &ssm.GetParameterInput{
+		Name:           aws.String(name),
+		WithDecryption: aws.Bool(s.decrypt),
+	})
+	if e != nil {
+		return nil, e
+	}
+	if out.Parameter == nil || out.Parameter.Value == nil {
+		return nil, ErrSecretNotFound
+	}
+
+	value := aws.StringValue(out.Parameter.Value)
+	if field == "" {
+		return TextSecret{Text: value}, nil
+	}
+
+	var fields map[string]string
+	if e := json.Unmarshal([]byte(value), &fields); e != nil {
+		return nil, e
+	}
+	return TextSecret{Text: fields[field]}, nil
+}
+
+func splitSSMRef(ref string) (name string, field string) {
+	if idx := strings.Index(ref, "#"); idx >= 0 {
+		return ref[:idx], ref[idx+1:]
+	}
+	return ref, ""
+}
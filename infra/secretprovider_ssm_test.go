@@ -0,0 +1,12 @@
+package infra
+
+import "testing"
+
+func TestSplitSSMRef(t *testing.T) {
+	if name, field := splitSSMRef("/banai/prod/deploy#password"); name != "/banai/prod/deploy" || field != "password" {
+		t.Fatalf("splitSSMRef = %q, %q, want %q, %q", name, field, "/banai/prod/deploy", "password")
+	}
+	if name, field := splitSSMRef("/banai/prod/deploy"); name != "/banai/prod/deploy" || field != "" {
+		t.Fatalf("splitSSMRef without '#' = %q, %q, want %q, %q", name, field, "/banai/prod/deploy", "")
+	}
+}
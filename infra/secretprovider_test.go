@@ -0,0 +1,44 @@
+package infra
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+type stubProvider struct{}
+
+func (stubProvider) Kind() string { return "stub" }
+
+func (stubProvider) Fetch(ctx context.Context, ref string) (SecretInfo, error) {
+	return TextSecret{Text: "stub:" + ref}, nil
+}
+
+func TestAddProviderTakesASingleOptionsObject(t *testing.T) {
+	RegisterProviderFactory("stub-test", func(config map[string]interface{}) (SecretProvider, error) {
+		return stubProvider{}, nil
+	})
+
+	b := NewBanai()
+	defer b.Close()
+	defer os.RemoveAll(b.TmpDir)
+
+	// this is the call shape the request documents:
+	// banai.secrets.addProvider({name, type, config})
+	e := b.Secrets.AddProvider(ProviderOptions{
+		Name:   "stubtest",
+		Type:   "stub-test",
+		Config: map[string]interface{}{},
+	})
+	if e != nil {
+		t.Fatalf("AddProvider: %v", e)
+	}
+
+	info, e := b.GetSecret("stubtest:hello")
+	if e != nil {
+		t.Fatalf("GetSecret: %v", e)
+	}
+	if got := info.(TextSecret).Text; got != "stub:hello" {
+		t.Fatalf("GetSecret text = %q, want %q", got, "stub:hello")
+	}
+}
@@ -0,0 +1,199 @@
+package infra
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	vaultapi "github.com/hashicorp/vault/api"
+)
+
+func init() {
+	RegisterProviderFactory("vault", newVaultProviderFromConfig)
+}
+
+//VaultConfig configures a VaultProvider
+type VaultConfig struct {
+	Address   string `json:"address"`
+	Token     string `json:"token"`
+	Namespace string `json:"namespace,omitempty"`
+	Mount     string `json:"mount,omitempty"`
+	KVVersion int    `json:"kvVersion,omitempty"`
+}
+
+//VaultProvider fetches secrets from a HashiCorp Vault KV secrets engine
+type VaultProvider struct {
+	client    *vaultapi.Client
+	mount     string
+	kvVersion int
+}
+
+//NewVaultProvider builds a VaultProvider from cfg
+func NewVaultProvider(cfg VaultConfig) (*VaultProvider, error) {
+	conf := vaultapi.DefaultConfig()
+	if cfg.Address != "" {
+		conf.Address = cfg.Address
+	}
+
+	client, e := vaultapi.NewClient(conf)
+	if e != nil {
+		return nil, e
+	}
+	if cfg.Token != "" {
+		client.SetToken(cfg.Token)
+	}
+	if cfg.Namespace != "" {
+		client.SetNamespace(cfg.Namespace)
+	}
+
+	mount := cfg.Mount
+	if mount == "" {
+		mount = "secret"
+	}
+	kvVersion := cfg.KVVersion
+	if kvVersion == 0 {
+		kvVersion = 2
+	}
+
+	return &VaultProvider{
+		client:    client,
+		mount:     mount,
+		kvVersion: kvVersion,
+	}, nil
+}
+
+func newVaultProviderFromConfig(config map[string]interface{}) (SecretProvider, error) {
+	cfg := VaultConfig{
+		Address:   configString(config, "address"),
+		Token:     configString(config, "token"),
+		Namespace: configString(config, "namespace"),
+		Mount:     configString(config, "mount"),
+	}
+	if v, ok := config["kvVersion"].(float64); ok {
+		cfg.KVVersion = int(v)
+	}
+
+	return NewVaultProvider(cfg)
+}
+
+//Kind identifies this provider as "vault"
+func (v *VaultProvider) Kind() string {
+	return "vault"
+}
+
+//Fetch reads path from Vault and maps the returned fields into a SecretInfo.
+//ref is a KV path relative to the configured mount, optionally followed by
+//"#field" (see vaultFieldsToSecretInfo), e.g. "deploy/github#private_key",
+//which with KV v2 is read from "<mount>/data/deploy/github".
+func (v *VaultProvider) Fetch(ctx context.Context, ref string) (SecretInfo, error) {
+	path, field := splitVaultRef(ref)
+
+	var data map[string]interface{}
+	var e error
+	if v.kvVersion == 2 {
+		data, e = v.readKVv2(ctx, path)
+	} else {
+		data, e = v.readKVv1(ctx, path)
+	}
+	if e != nil {
+		return nil, e
+	}
+
+	return vaultFieldsToSecretInfo(ctx, data, field)
+}
+
+func splitVaultRef(ref string) (path string, field string) {
+	if idx := strings.Index(ref, "#"); idx >= 0 {
+		return ref[:idx], ref[idx+1:]
+	}
+	return ref, ""
+}
+
+func (v *VaultProvider) readKVv2(ctx context.Context, path string) (map[string]interface{}, error) {
+	secret, e := v.client.Logical().ReadWithContext(ctx, fmt.Sprintf("%s/data/%s", v.mount, path))
+	if e != nil {
+		return nil, e
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, ErrSecretNotFound
+	}
+
+	data, ok := secret.Data["data"].(map[string]interface{})
+	if !ok {
+		return nil, ErrSecretNotFound
+	}
+	return data, nil
+}
+
+func (v *VaultProvider) readKVv1(ctx context.Context, path string) (map[string]interface{}, error) {
+	secret, e := v.client.Logical().ReadWithContext(ctx, fmt.Sprintf("%s/%s", v.mount, path))
+	if e != nil {
+		return nil, e
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, ErrSecretNotFound
+	}
+	return secret.Data, nil
+}
+
+//vaultFieldsToSecretInfo maps the raw KV fields of a Vault secret into the
+//matching SecretInfo shape. A requested field selects that single field as
+//text, except "private_key", which yields the whole SSH secret (user,
+//private_key and passphrase fields).
+func vaultFieldsToSecretInfo(ctx context.Context, data map[string]interface{}, field string) (SecretInfo, error) {
+	str := func(key string) string {
+		s, _ := data[key].(string)
+		return s
+	}
+
+	switch {
+	case field == "" && str("private_key") != "", field == "private_key":
+		fn, e := writeTempPrivateKey(ctx, str("private_key"))
+		if e != nil {
+			return nil, e
+		}
+		return SSHWithPrivate{
+			User:           str("user"),
+			PrivatekeyFile: fn,
+			Passfrase:      str("passphrase"),
+			rawPrivateKey:  str("private_key"),
+		}, nil
+	case field == "" && str("user") != "" && str("password") != "":
+		return UserPassword{
+			User:     str("user"),
+			Password: str("password"),
+		}, nil
+	case field != "":
+		return TextSecret{Text: str(field)}, nil
+	default:
+		return TextSecret{Text: str("value")}, nil
+	}
+}
+
+//writeTempPrivateKey materializes key to disk so it can be handed to an SSH
+//client as a file path. It writes under the secret folder stashed in ctx by
+//GetSecret, so the file gets removed along with every other secret-backed
+//key file when Close() removes that folder; it falls back to the OS temp dir when used outside a
+//Banai run (e.g. calling a provider's Fetch directly).
+func writeTempPrivateKey(ctx context.Context, key string) (string, error) {
+	dir, ok := secretFolderFromContext(ctx)
+	if !ok {
+		dir = ""
+	}
+
+	f, e := ioutil.TempFile(dir, "banai-ssh-")
+	if e != nil {
+		return "", e
+	}
+	defer f.Close()
+
+	if _, e := f.WriteString(key); e != nil {
+		return "", e
+	}
+	if e := os.Chmod(f.Name(), 0600); e != nil {
+		return "", e
+	}
+	return f.Name(), nil
+}
@@ -0,0 +1,199 @@
+package infra
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/nacl/secretbox"
+)
+
+const (
+	storeSaltSize  = 16
+	storeNonceSize = 24
+)
+
+//secretStoreState remembers where the persistent secret store lives and the
+//key derived from its passphrase, so SaveSecretStore can be called with no
+//arguments after LoadSecretStore unlocked it
+type secretStoreState struct {
+	path string
+	salt []byte
+	key  [32]byte
+}
+
+func (s *secretStoreState) unlocked() bool {
+	return s.path != ""
+}
+
+//persistedSecret is the on-disk representation of one secretEntry
+type persistedSecret struct {
+	ID        string            `json:"id"`
+	Type      string            `json:"type"`
+	Data      json.RawMessage   `json:"data"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	CreatedAt time.Time         `json:"createdAt"`
+	TTL       time.Duration     `json:"ttl,omitempty"`
+}
+
+//LoadSecretStore unlocks the encrypted secret store at path using passphrase
+//and loads its entries into b, replacing whatever secrets are already held in
+//memory. A path that does not yet exist starts a new store: the secrets
+//already held in memory are kept, and SaveSecretStore writes them to create
+//the file on disk.
+func (b Banai) LoadSecretStore(path string, passphrase string) error {
+	raw, e := ioutil.ReadFile(path)
+	if os.IsNotExist(e) {
+		salt := make([]byte, storeSaltSize)
+		if _, e := rand.Read(salt); e != nil {
+			return e
+		}
+		*b.store = secretStoreState{
+			path: path,
+			salt: salt,
+			key:  deriveStoreKey(passphrase, salt),
+		}
+		return nil
+	}
+	if e != nil {
+		return e
+	}
+
+	if len(raw) < storeSaltSize+storeNonceSize {
+		return errors.New("secret store file is corrupt")
+	}
+	salt := raw[:storeSaltSize]
+	var nonce [storeNonceSize]byte
+	copy(nonce[:], raw[storeSaltSize:storeSaltSize+storeNonceSize])
+	ciphertext := raw[storeSaltSize+storeNonceSize:]
+
+	key := deriveStoreKey(passphrase, salt)
+
+	plain, ok := secretbox.Open(nil, ciphertext, &nonce, &key)
+	if !ok {
+		return errors.New("failed to unlock secret store: wrong passphrase or corrupt file")
+	}
+
+	var persisted []persistedSecret
+	if e := json.Unmarshal(plain, &persisted); e != nil {
+		return e
+	}
+
+	for id, e := range b.secrets {
+		if e.keyFile != "" {
+			shredFile(e.keyFile)
+		}
+		delete(b.secrets, id)
+		b.redactor.unregister(id)
+	}
+	for _, p := range persisted {
+		entry, e := persistedToEntry(p)
+		if e != nil {
+			return e
+		}
+		b.secrets[p.ID] = entry
+		b.redactor.registerSecretStruct(p.ID, entry.value)
+	}
+
+	*b.store = secretStoreState{
+		path: path,
+		salt: salt,
+		key:  key,
+	}
+	return nil
+}
+
+//SaveSecretStore encrypts the secrets currently held in b and writes them to
+//the path last unlocked with LoadSecretStore
+func (b Banai) SaveSecretStore() error {
+	if !b.store.unlocked() {
+		return errors.New("secret store was never unlocked with LoadSecretStore")
+	}
+
+	persisted := make([]persistedSecret, 0, len(b.secrets))
+	for id, e := range b.secrets {
+		p, err := entryToPersisted(id, e)
+		if err != nil {
+			return err
+		}
+		persisted = append(persisted, p)
+	}
+
+	plain, e := json.Marshal(persisted)
+	if e != nil {
+		return e
+	}
+
+	var nonce [storeNonceSize]byte
+	if _, e := rand.Read(nonce[:]); e != nil {
+		return e
+	}
+
+	sealed := secretbox.Seal(nil, plain, &nonce, &b.store.key)
+
+	out := make([]byte, 0, storeSaltSize+storeNonceSize+len(sealed))
+	out = append(out, b.store.salt...)
+	out = append(out, nonce[:]...)
+	out = append(out, sealed...)
+
+	return ioutil.WriteFile(b.store.path, out, 0600)
+}
+
+func deriveStoreKey(passphrase string, salt []byte) [32]byte {
+	var key [32]byte
+	copy(key[:], argon2.IDKey([]byte(passphrase), salt, 1, 64*1024, 4, 32))
+	return key
+}
+
+func entryToPersisted(id string, e secretEntry) (persistedSecret, error) {
+	data, err := json.Marshal(e.value)
+	if err != nil {
+		return persistedSecret{}, err
+	}
+	return persistedSecret{
+		ID:        id,
+		Type:      e.value.GetType(),
+		Data:      data,
+		Labels:    e.options.Labels,
+		CreatedAt: e.createdAt,
+		TTL:       e.options.TTL,
+	}, nil
+}
+
+func persistedToEntry(p persistedSecret) (secretEntry, error) {
+	var value secretStruct
+
+	switch p.Type {
+	case "text":
+		var v secretText
+		if e := json.Unmarshal(p.Data, &v); e != nil {
+			return secretEntry{}, e
+		}
+		value = v
+	case "ssh":
+		var v secretSSHWithPrivate
+		if e := json.Unmarshal(p.Data, &v); e != nil {
+			return secretEntry{}, e
+		}
+		value = v
+	case "userpass":
+		var v secretUserPassword
+		if e := json.Unmarshal(p.Data, &v); e != nil {
+			return secretEntry{}, e
+		}
+		value = v
+	default:
+		return secretEntry{}, fmt.Errorf("unknown persisted secret type %q", p.Type)
+	}
+
+	return secretEntry{
+		value:     value,
+		options:   SecretOptions{Labels: p.Labels, TTL: p.TTL},
+		createdAt: p.CreatedAt,
+	}, nil
+}
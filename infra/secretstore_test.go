@@ -0,0 +1,51 @@
+package infra
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSecretStoreRoundTripAndRedaction(t *testing.T) {
+	root := t.TempDir()
+	storePath := filepath.Join(root, "secrets.enc")
+
+	b1 := NewBanai()
+	defer b1.Close()
+	defer os.RemoveAll(b1.TmpDir)
+
+	b1.AddStringSecret("db-pass", "hunter2-supersecret")
+	if e := b1.LoadSecretStore(storePath, "correct horse battery staple"); e != nil {
+		t.Fatalf("LoadSecretStore (create): %v", e)
+	}
+	if e := b1.SaveSecretStore(); e != nil {
+		t.Fatalf("SaveSecretStore: %v", e)
+	}
+
+	b2 := NewBanai()
+	defer b2.Close()
+	defer os.RemoveAll(b2.TmpDir)
+
+	if e := b2.LoadSecretStore(storePath, "correct horse battery staple"); e != nil {
+		t.Fatalf("LoadSecretStore (unlock): %v", e)
+	}
+
+	info, e := b2.GetSecret("db-pass")
+	if e != nil {
+		t.Fatalf("GetSecret: %v", e)
+	}
+	if got := info.(TextSecret).Text; got != "hunter2-supersecret" {
+		t.Fatalf("GetSecret text = %q, want %q", got, "hunter2-supersecret")
+	}
+
+	// secrets loaded in bulk from the persistent store must be redacted from
+	// logs just like ones added through AddStringSecret
+	if got := b2.redactor.redact("leaking hunter2-supersecret here"); strings.Contains(got, "hunter2-supersecret") {
+		t.Fatalf("secret value leaked through redactor after LoadSecretStore: %q", got)
+	}
+
+	if e := b2.LoadSecretStore(storePath, "wrong passphrase"); e == nil {
+		t.Fatal("LoadSecretStore with wrong passphrase should fail")
+	}
+}
@@ -0,0 +1,349 @@
+package infra
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+const (
+	stashMinChunk = 256 * 1024
+	stashMaxChunk = 1024 * 1024
+	stashMaskBits = 19 // past stashMinChunk, a boundary every 2^19 = 512KiB on average
+)
+
+//stashEntry is one file or directory inside a stash manifest. Type tells
+//materialize which of Chunks/Children to use - an empty directory has no
+//children, so Chunks/Children being empty cannot be trusted to tell file and
+//dir apart on their own.
+type stashEntry struct {
+	Name     string       `json:"name"`
+	Type     string       `json:"type"`
+	Mode     uint32       `json:"mode"`
+	Chunks   []string     `json:"chunks,omitempty"`
+	Children []stashEntry `json:"children,omitempty"`
+}
+
+//stashManifest is the content-addressed record of one Save call
+type stashManifest struct {
+	Type    string       `json:"type"`
+	Entries []stashEntry `json:"entries"`
+}
+
+//StashInfo summarizes a stashed item without reading any of its chunk data
+type StashInfo struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Entries int    `json:"entries"`
+}
+
+func (b Banai) objectsDir() string {
+	return filepath.Join(b.stashFolder, "objects")
+}
+
+func (b Banai) manifestsDir() string {
+	return filepath.Join(b.stashFolder, "manifests")
+}
+
+//Save stashes the content of fileName, which may be a regular file or a
+//directory tree, splitting it into content-addressed chunks deduplicated
+//against anything already stashed, and returns the resulting stash ID
+func (b Banai) Save(fileName string) (string, error) {
+	abs, e := filepath.Abs(fileName)
+	if e != nil {
+		return "", e
+	}
+
+	info, e := os.Stat(abs)
+	if e != nil {
+		return "", e
+	}
+
+	var manifest stashManifest
+	if info.IsDir() {
+		entries, e := b.stashDir(abs)
+		if e != nil {
+			return "", e
+		}
+		manifest = stashManifest{Type: "dir", Entries: entries}
+	} else {
+		entry, e := b.stashFile(abs, info)
+		if e != nil {
+			return "", e
+		}
+		manifest = stashManifest{Type: "file", Entries: []stashEntry{entry}}
+	}
+
+	return b.writeManifest(manifest)
+}
+
+//Load returns the content of a previously stashed file. It fails for
+//directory stashes; use LoadTo for those.
+func (b Banai) Load(stashID string) ([]byte, error) {
+	manifest, e := b.readManifest(stashID)
+	if e != nil {
+		return nil, e
+	}
+	if manifest.Type != "file" || len(manifest.Entries) != 1 {
+		return nil, fmt.Errorf("stash %q is not a single file, use LoadTo", stashID)
+	}
+
+	return b.readChunks(manifest.Entries[0].Chunks)
+}
+
+//LoadTo materializes a previously stashed file or directory tree under
+//destDir, preserving file modes
+func (b Banai) LoadTo(stashID string, destDir string) error {
+	manifest, e := b.readManifest(stashID)
+	if e != nil {
+		return e
+	}
+	return b.materialize(manifest.Entries, destDir)
+}
+
+//StashList enumerates the IDs of everything currently stashed
+func (b Banai) StashList() ([]string, error) {
+	items, e := ioutil.ReadDir(b.manifestsDir())
+	if os.IsNotExist(e) {
+		return nil, nil
+	}
+	if e != nil {
+		return nil, e
+	}
+
+	ids := make([]string, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.Name())
+	}
+	return ids, nil
+}
+
+//StashStat describes a stash ID without reading its chunk content
+func (b Banai) StashStat(stashID string) (StashInfo, error) {
+	manifest, e := b.readManifest(stashID)
+	if e != nil {
+		return StashInfo{}, e
+	}
+
+	return StashInfo{
+		ID:      stashID,
+		Type:    manifest.Type,
+		Entries: len(manifest.Entries),
+	}, nil
+}
+
+//*********************************************************************************
+
+func (b Banai) stashDir(path string) ([]stashEntry, error) {
+	items, e := ioutil.ReadDir(path)
+	if e != nil {
+		return nil, e
+	}
+
+	entries := make([]stashEntry, 0, len(items))
+	for _, item := range items {
+		childPath := filepath.Join(path, item.Name())
+
+		if item.IsDir() {
+			children, e := b.stashDir(childPath)
+			if e != nil {
+				return nil, e
+			}
+			entries = append(entries, stashEntry{
+				Name:     item.Name(),
+				Type:     "dir",
+				Mode:     uint32(item.Mode()),
+				Children: children,
+			})
+			continue
+		}
+
+		entry, e := b.stashFile(childPath, item)
+		if e != nil {
+			return nil, e
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (b Banai) stashFile(path string, info os.FileInfo) (stashEntry, error) {
+	f, e := os.Open(path)
+	if e != nil {
+		return stashEntry{}, e
+	}
+	defer f.Close()
+
+	chunks, e := b.storeChunks(f)
+	if e != nil {
+		return stashEntry{}, e
+	}
+
+	return stashEntry{
+		Name:   info.Name(),
+		Type:   "file",
+		Mode:   uint32(info.Mode()),
+		Chunks: chunks,
+	}, nil
+}
+
+//storeChunks splits r into content-defined chunks (FastCDC style, 256KiB to
+//1MiB) and writes each one once, keyed by its SHA-256, returning the
+//ordered list of chunk hashes
+func (b Banai) storeChunks(r io.Reader) ([]string, error) {
+	objDir := b.objectsDir()
+	if e := os.MkdirAll(objDir, 0700); e != nil {
+		return nil, e
+	}
+
+	src := bufio.NewReader(r)
+	var hashes []string
+	for {
+		chunk, e := readChunk(src)
+		if e != nil {
+			return nil, e
+		}
+		if len(chunk) == 0 {
+			break
+		}
+
+		sum := sha256.Sum256(chunk)
+		hash := hex.EncodeToString(sum[:])
+		path := filepath.Join(objDir, hash)
+		if _, e := os.Stat(path); os.IsNotExist(e) {
+			if e := ioutil.WriteFile(path, chunk, 0600); e != nil {
+				return nil, e
+			}
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, nil
+}
+
+func (b Banai) readChunks(hashes []string) ([]byte, error) {
+	var out []byte
+	for _, hash := range hashes {
+		chunk, e := ioutil.ReadFile(filepath.Join(b.objectsDir(), hash))
+		if e != nil {
+			return nil, e
+		}
+		out = append(out, chunk...)
+	}
+	return out, nil
+}
+
+func (b Banai) writeManifest(manifest stashManifest) (string, error) {
+	data, e := json.Marshal(manifest)
+	if e != nil {
+		return "", e
+	}
+
+	sum := sha256.Sum256(data)
+	id := hex.EncodeToString(sum[:])
+
+	dir := b.manifestsDir()
+	if e := os.MkdirAll(dir, 0700); e != nil {
+		return "", e
+	}
+
+	path := filepath.Join(dir, id)
+	if _, e := os.Stat(path); os.IsNotExist(e) {
+		if e := ioutil.WriteFile(path, data, 0600); e != nil {
+			return "", e
+		}
+	}
+	return id, nil
+}
+
+func (b Banai) readManifest(stashID string) (stashManifest, error) {
+	data, e := ioutil.ReadFile(filepath.Join(b.manifestsDir(), stashID))
+	if e != nil {
+		return stashManifest{}, e
+	}
+
+	var manifest stashManifest
+	if e := json.Unmarshal(data, &manifest); e != nil {
+		return stashManifest{}, e
+	}
+	return manifest, nil
+}
+
+func (b Banai) materialize(entries []stashEntry, destDir string) error {
+	if e := os.MkdirAll(destDir, 0700); e != nil {
+		return e
+	}
+
+	for _, entry := range entries {
+		path := filepath.Join(destDir, entry.Name)
+
+		if entry.Type == "dir" {
+			if e := b.materialize(entry.Children, path); e != nil {
+				return e
+			}
+			if e := os.Chmod(path, os.FileMode(entry.Mode)); e != nil {
+				return e
+			}
+			continue
+		}
+
+		data, e := b.readChunks(entry.Chunks)
+		if e != nil {
+			return e
+		}
+		if e := ioutil.WriteFile(path, data, os.FileMode(entry.Mode)); e != nil {
+			return e
+		}
+	}
+
+	return nil
+}
+
+//gearTable drives the rolling hash readChunk uses to pick chunk boundaries.
+//It only needs to be a fixed, well-mixed table - not cryptographically
+//random - so chunking is deterministic across runs and machines.
+var gearTable = func() [256]uint64 {
+	var table [256]uint64
+	state := uint64(0x9e3779b97f4a7c15)
+	for i := range table {
+		state = state*6364136223846793005 + 1442695040888963407
+		table[i] = state
+	}
+	return table
+}()
+
+//readChunk reads a single content-defined chunk from r, stopping once the
+//rolling hash hits a boundary after stashMinChunk bytes, or at stashMaxChunk
+//regardless, or at EOF
+func readChunk(r *bufio.Reader) ([]byte, error) {
+	mask := uint64(1)<<stashMaskBits - 1
+	var hash uint64
+	buf := make([]byte, 0, stashMinChunk)
+
+	for {
+		c, e := r.ReadByte()
+		if e == io.EOF {
+			break
+		}
+		if e != nil {
+			return nil, e
+		}
+
+		buf = append(buf, c)
+		hash = (hash << 1) + gearTable[c]
+
+		if len(buf) >= stashMinChunk && hash&mask == 0 {
+			break
+		}
+		if len(buf) >= stashMaxChunk {
+			break
+		}
+	}
+
+	return buf, nil
+}
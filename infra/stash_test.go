@@ -0,0 +1,91 @@
+package infra
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStashRoundTripPreservesEmptyDirsAndModes(t *testing.T) {
+	root := t.TempDir()
+
+	src := filepath.Join(root, "src")
+	if e := os.MkdirAll(filepath.Join(src, "empty"), 0750); e != nil {
+		t.Fatal(e)
+	}
+	if e := os.WriteFile(filepath.Join(src, "file.txt"), []byte("hello"), 0640); e != nil {
+		t.Fatal(e)
+	}
+
+	b := NewBanai()
+	defer b.Close()
+	defer os.RemoveAll(b.TmpDir)
+	b.TmpDir = filepath.Join(root, "banai")
+	b.stashFolder = filepath.Join(b.TmpDir, "stash")
+
+	id, e := b.Save(src)
+	if e != nil {
+		t.Fatalf("Save: %v", e)
+	}
+
+	dest := filepath.Join(root, "dest")
+	if e := b.LoadTo(id, dest); e != nil {
+		t.Fatalf("LoadTo: %v", e)
+	}
+
+	info, e := os.Stat(filepath.Join(dest, "empty"))
+	if e != nil {
+		t.Fatalf("restored empty dir: %v", e)
+	}
+	if !info.IsDir() {
+		t.Fatalf("%q restored as a file, want a directory", filepath.Join(dest, "empty"))
+	}
+	if info.Mode().Perm() != 0750 {
+		t.Fatalf("restored dir mode = %o, want %o", info.Mode().Perm(), 0750)
+	}
+
+	fileInfo, e := os.Stat(filepath.Join(dest, "file.txt"))
+	if e != nil {
+		t.Fatalf("restored file: %v", e)
+	}
+	if fileInfo.Mode().Perm() != 0640 {
+		t.Fatalf("restored file mode = %o, want %o", fileInfo.Mode().Perm(), 0640)
+	}
+}
+
+func TestStashSurvivesFreshBanaiInstance(t *testing.T) {
+	root := t.TempDir()
+	tmpDir := filepath.Join(root, "banai")
+
+	b1 := NewBanai()
+	defer os.RemoveAll(b1.TmpDir)
+	b1.TmpDir = tmpDir
+	b1.stashFolder = filepath.Join(tmpDir, "stash")
+
+	src := filepath.Join(root, "file.txt")
+	if e := os.WriteFile(src, []byte("persisted across runs"), 0640); e != nil {
+		t.Fatal(e)
+	}
+
+	id, e := b1.Save(src)
+	if e != nil {
+		t.Fatalf("Save: %v", e)
+	}
+	b1.Close()
+
+	// a fresh Banai pointed at the same TmpDir simulates a new process
+	// against the same stash directory - it must not have wiped it
+	b2 := NewBanai()
+	defer b2.Close()
+	defer os.RemoveAll(b2.TmpDir)
+	b2.TmpDir = tmpDir
+	b2.stashFolder = filepath.Join(tmpDir, "stash")
+
+	data, e := b2.Load(id)
+	if e != nil {
+		t.Fatalf("Load after fresh NewBanai: %v", e)
+	}
+	if string(data) != "persisted across runs" {
+		t.Fatalf("Load = %q, want %q", data, "persisted across runs")
+	}
+}